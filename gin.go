@@ -2,22 +2,33 @@ package logging
 
 import (
 	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httputil"
 	"os"
 	"path"
+	"regexp"
 	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
+// traceparentHeader W3C Trace Context 标准定义的 header 名
+const traceparentHeader = "traceparent"
+
 // GetGinTraceIDFromHeader 从 gin 的 request header 中获取 key 为 TraceIDKeyname 的值作为 traceid
 func GetGinTraceIDFromHeader(c *gin.Context) string {
 	return c.Request.Header.Get(string(TraceIDKeyname))
@@ -33,6 +44,77 @@ func GetGinTraceIDFromPostForm(c *gin.Context) string {
 	return c.PostForm(string(TraceIDKeyname))
 }
 
+// GetGinTraceIDFromTraceparent 解析 W3C traceparent header（`00-<32hex trace-id>-<16hex span-id>-<flags>`），
+// 校验合法后返回其中的 32 位十六进制 trace-id，解析失败或缺失时返回空字符串
+func GetGinTraceIDFromTraceparent(c *gin.Context) string {
+	traceID, _, ok := parseTraceparent(c.Request.Header.Get(traceparentHeader))
+	if !ok {
+		return ""
+	}
+	return traceID
+}
+
+// parseTraceparent 校验并解析 traceparent header，version 目前只支持 "00"
+// trace-id、span-id 全 0 视为非法（W3C 规范保留值）
+func parseTraceparent(header string) (traceID string, spanID string, ok bool) {
+	parts := strings.Split(header, "-")
+	if len(parts) != 4 {
+		return "", "", false
+	}
+	version, tid, sid, flags := parts[0], parts[1], parts[2], parts[3]
+	if version != "00" || len(tid) != 32 || len(sid) != 16 || len(flags) != 2 {
+		return "", "", false
+	}
+	if !isHexString(tid) || !isHexString(sid) || !isHexString(flags) {
+		return "", "", false
+	}
+	if tid == strings.Repeat("0", 32) || sid == strings.Repeat("0", 16) {
+		return "", "", false
+	}
+	return strings.ToLower(tid), strings.ToLower(sid), true
+}
+
+func isHexString(s string) bool {
+	for _, r := range s {
+		if !((r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')) {
+			return false
+		}
+	}
+	return true
+}
+
+// toW3CTraceID 把 traceID 归一化成 32 位十六进制字符串，本身已合法时原样返回（小写），
+// 否则（如业务自定义的 uuid、短 id）取其 md5 摘要，保证生成的 traceparent 始终合法
+func toW3CTraceID(traceID string) string {
+	if len(traceID) == 32 && isHexString(traceID) {
+		return strings.ToLower(traceID)
+	}
+	sum := md5.Sum([]byte(traceID))
+	return hex.EncodeToString(sum[:])
+}
+
+// buildTraceparent 按 W3C 规范拼装 traceparent header 值，sampled flag 固定为 01
+func buildTraceparent(traceID, spanID string) string {
+	return fmt.Sprintf("00-%s-%s-01", traceID, spanID)
+}
+
+// newRandomHexID 生成 n 字节的随机十六进制字符串（长度为 2n），用于生成 span-id
+func newRandomHexID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand 读取失败概率极低，兜底使用当前时间填充，保证不会 panic
+		for i := range b {
+			b[i] = byte(time.Now().UnixNano() >> uint(i))
+		}
+	}
+	return hex.EncodeToString(b)
+}
+
+// defaultGinSpanIDFunc 默认的 span id 获取函数：每个请求在当前服务生成一个新的 span
+func defaultGinSpanIDFunc(c *gin.Context) string {
+	return newRandomHexID(8)
+}
+
 // GinLogMsg gin 日志中间件记录的信息
 type GinLogMsg struct {
 	// 日志打印时间
@@ -73,10 +155,20 @@ type GinLogMsg struct {
 	ContextKeys map[string]interface{} `json:"context_keys,omitempty"`
 	// RequestBody 请求 body
 	RequestBody string `json:"request_body,omitempty"`
+	// RequestBodyTruncated 请求 body 是否因超过 MaxBodyLogBytes 被截断
+	RequestBodyTruncated bool `json:"request_body_truncated,omitempty"`
 	// ResponseBody 响应 Body
 	ResponseBody string `json:"response_body,omitempty"`
+	// ResponseBodyTruncated 响应 body 是否因超过 MaxBodyLogBytes 被截断
+	ResponseBodyTruncated bool `json:"response_body_truncated,omitempty"`
 }
 
+// defaultMaxBodyLogBytes 记录 body 时默认保留的最大字节数
+const defaultMaxBodyLogBytes = 4 * 1024
+
+// defaultBodyLogContentTypes 默认只记录这些 Content-Type 的 body，避免把二进制内容写进日志
+var defaultBodyLogContentTypes = []string{"application/json", "application/x-www-form-urlencoded", "text/*"}
+
 // GinLoggerConfig GinLogger 支持的配置项字段定义
 type GinLoggerConfig struct {
 	// Optional. Default value is logging.defaultGinLogFormatter
@@ -96,6 +188,25 @@ type GinLoggerConfig struct {
 	// TraceIDFunc 获取或生成 trace id 的函数
 	// Optional.
 	TraceIDFunc func(*gin.Context) string
+	// SpanIDFunc 获取或生成 span id 的函数，默认每个请求生成一个新的 span id
+	// Optional.
+	SpanIDFunc func(*gin.Context) string
+	// Sampler 按 (path, status class) 维度对 2xx/3xx 访问日志做 token-bucket 采样，语义与 zap 的 sampling 一致
+	// 4xx/5xx 以及带 c.Errors 的请求永远不采样，始终打印
+	// Optional.
+	Sampler *Sampler
+	// ErrorHook 请求带有 c.Errors 时的回调，接收原始 error 列表，可用于上报 Sentry/告警而无需重新解析日志行
+	// Optional.
+	ErrorHook func(*gin.Context, []*gin.Error)
+	// Sink 访问日志的最终写出方式
+	// Optional. Default 基于 CtxLogger(c) 构造一个 NewZapSink，行为与之前版本一致
+	Sink Sink
+	// MaxBodyLogBytes 记录请求/响应 body 时单个 body 保留的最大字节数，超出部分会被截断，只在 DetailsWithBody 为 true 时生效
+	// Optional. Default 4096 (4KB)
+	MaxBodyLogBytes int
+	// BodyLogContentTypes 只有 Content-Type 命中该列表才记录 body，支持 "text/*" 这种前缀通配，只在 DetailsWithBody 为 true 时生效
+	// Optional. Default []string{"application/json", "application/x-www-form-urlencoded", "text/*"}
+	BodyLogContentTypes []string
 }
 
 // GinLogger 以默认配置生成 gin 的 Logger 中间件
@@ -120,6 +231,10 @@ func defaultGinLogFormatter(m GinLogMsg) string {
 }
 
 func defaultGinTraceIDFunc(c *gin.Context) (traceID string) {
+	traceID = GetGinTraceIDFromTraceparent(c)
+	if traceID != "" {
+		return
+	}
 	traceID = GetGinTraceIDFromHeader(c)
 	if traceID != "" {
 		return
@@ -136,6 +251,100 @@ func defaultGinTraceIDFunc(c *gin.Context) (traceID string) {
 	return
 }
 
+// Sampler 定义按 (path, status class) 维度的访问日志采样策略，语义与 zap 的 sampling 一致：
+// 每个 Tick 窗口内前 Initial 条日志全部打印，之后每 Thereafter 条打印一条
+type Sampler struct {
+	// Initial 窗口内无条件打印的日志条数
+	Initial int
+	// Thereafter 超过 Initial 后每隔多少条打印一条，<= 0 时按 1 处理（即不再额外抽样）
+	Thereafter int
+	// Tick 采样窗口大小，<= 0 时默认为 1s
+	Tick time.Duration
+}
+
+// statusClass 把状态码归类为 "2xx"/"3xx"/"4xx"/"5xx"，用作采样 key 的一部分
+func statusClass(statusCode int) string {
+	switch statusCode / 100 {
+	case 2:
+		return "2xx"
+	case 3:
+		return "3xx"
+	case 4:
+		return "4xx"
+	default:
+		return "5xx"
+	}
+}
+
+// classifyGinErrors 按 gin.ErrorType 把 c.Errors 分类，Bind/Public 单独归类，其余（Private/Any）归入 private
+func classifyGinErrors(errs []*gin.Error) (bindErrors, publicErrors, privateErrors []string) {
+	for _, e := range errs {
+		switch e.Type {
+		case gin.ErrorTypeBind:
+			bindErrors = append(bindErrors, e.Error())
+		case gin.ErrorTypePublic:
+			publicErrors = append(publicErrors, e.Error())
+		default:
+			privateErrors = append(privateErrors, e.Error())
+		}
+	}
+	return
+}
+
+// sampleCounter 记录某个采样 key 在当前窗口内的计数
+type sampleCounter struct {
+	resetAt time.Time
+	count   int
+}
+
+// ginSampler 是 Sampler 的运行时状态，需要在多个请求间共享，随 GinLoggerWithConfig 的返回值一起存活
+type ginSampler struct {
+	initial    int
+	thereafter int
+	tick       time.Duration
+
+	mu       sync.Mutex
+	counters map[string]*sampleCounter
+}
+
+func newGinSampler(conf *Sampler) *ginSampler {
+	if conf == nil {
+		return nil
+	}
+	thereafter := conf.Thereafter
+	if thereafter <= 0 {
+		thereafter = 1
+	}
+	tick := conf.Tick
+	if tick <= 0 {
+		tick = time.Second
+	}
+	return &ginSampler{
+		initial:    conf.Initial,
+		thereafter: thereafter,
+		tick:       tick,
+		counters:   make(map[string]*sampleCounter),
+	}
+}
+
+// allow 返回 key 对应的这一条日志本次是否应该打印
+func (s *ginSampler) allow(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	counter, exists := s.counters[key]
+	if !exists || now.After(counter.resetAt) {
+		counter = &sampleCounter{resetAt: now.Add(s.tick)}
+		s.counters[key] = counter
+	}
+	counter.count++
+	if counter.count <= s.initial {
+		return true
+	}
+	return (counter.count-s.initial)%s.thereafter == 0
+}
+
 // GinLoggerWithConfig 根据配置信息生成 gin 的 Logger 中间件
 // 中间件会记录访问信息，根据状态码确定日志级别， 500 以上为 Error ， 400-500 默认为 Warn ， 400 以下默认为 Info
 // api 请求进来的 context 的函数无需在其中打印 err ，使用 c.Error(err)会在请求完成时自动打印 error
@@ -149,6 +358,19 @@ func GinLoggerWithConfig(conf GinLoggerConfig) gin.HandlerFunc {
 	if getTraceID == nil {
 		getTraceID = defaultGinTraceIDFunc
 	}
+	getSpanID := conf.SpanIDFunc
+	if getSpanID == nil {
+		getSpanID = defaultGinSpanIDFunc
+	}
+	maxBodyLogBytes := conf.MaxBodyLogBytes
+	if maxBodyLogBytes <= 0 {
+		maxBodyLogBytes = defaultMaxBodyLogBytes
+	}
+	bodyLogContentTypes := conf.BodyLogContentTypes
+	if len(bodyLogContentTypes) == 0 {
+		bodyLogContentTypes = defaultBodyLogContentTypes
+	}
+	sampler := newGinSampler(conf.Sampler)
 
 	var skip map[string]struct{}
 	if length := len(conf.SkipPaths); length > 0 {
@@ -158,11 +380,21 @@ func GinLoggerWithConfig(conf GinLoggerConfig) gin.HandlerFunc {
 		}
 	}
 	return func(c *gin.Context) {
+		hadTraceparent := GetGinTraceIDFromTraceparent(c) != ""
+
 		traceID := getTraceID(c)
+		spanID := getSpanID(c)
 		// 设置 trace id 到 request header 中
 		c.Request.Header.Set(string(TraceIDKeyname), traceID)
 		// 设置 trace id 到 response header 中
 		c.Writer.Header().Set(string(TraceIDKeyname), traceID)
+		// 请求没有携带合法的 traceparent 时，生成一个可以继续向下游传播的 W3C trace id，
+		// 使 OTel 组网中的下游服务能拿到连续的 trace
+		if !hadTraceparent {
+			tp := buildTraceparent(toW3CTraceID(traceID), spanID)
+			c.Request.Header.Set(traceparentHeader, tp)
+			c.Writer.Header().Set(traceparentHeader, tp)
+		}
 		// 设置 trace id 和 ctxLogger 到 context 中
 		Context(c, CloneLogger("gin"), traceID)
 
@@ -186,11 +418,16 @@ func GinLoggerWithConfig(conf GinLoggerConfig) gin.HandlerFunc {
 			HandlerName: c.HandlerName(),
 		}
 
-		// 开启记录响应 body 时，保存 body 到 rbw.body 中
-		rbw := &responseBodyWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
+		// 开启记录响应 body 时，保存 body 到 rbw.body 中，超过 maxBodyLogBytes 的部分会被截断
+		rbw := &responseBodyWriter{body: newLimitedBuffer(maxBodyLogBytes), ResponseWriter: c.Writer}
+		// reqBodyBuf 非空时，请求 body 会在被下游 handler 读取的过程中同步截断保存，避免整体读入内存
+		var reqBodyBuf *limitedBuffer
 		if !conf.DisableDetails && conf.DetailsWithBody {
-			// 获取并保存请求 body
-			msg.RequestBody = string(GetRequestBody(c))
+			if c.Request.Body != nil && shouldCaptureBody(msg.ContentType, c.Request.ContentLength, bodyLogContentTypes, maxBodyLogBytes) {
+				reqBodyBuf = newLimitedBuffer(maxBodyLogBytes)
+				// TeeReader 保证下游 handler 依然能完整读取原始 body，limitedBuffer 自行丢弃超过上限的部分
+				c.Request.Body = ioutil.NopCloser(io.TeeReader(c.Request.Body, reqBodyBuf))
+			}
 			c.Writer = rbw
 		}
 
@@ -207,16 +444,50 @@ func GinLoggerWithConfig(conf GinLoggerConfig) gin.HandlerFunc {
 			if !conf.DisableDetails && conf.DetailsWithContextKeys {
 				msg.ContextKeys = c.Keys
 			}
-			// 获取并保存响应 body
+			// 获取并保存请求、响应 body
 			if !conf.DisableDetails && conf.DetailsWithBody {
-				msg.ResponseBody = rbw.body.String()
+				if reqBodyBuf != nil {
+					msg.RequestBody = reqBodyBuf.String()
+					msg.RequestBodyTruncated = reqBodyBuf.Truncated()
+				}
+				// 响应 Content-Type 要到 c.Next() 结束、handler 写完 header 后才能拿到，
+				// 因此这里才按 BodyLogContentTypes 过滤，跳过图片/二进制下载等不该记录的响应
+				responseContentType := rbw.Header().Get("Content-Type")
+				if shouldCaptureBody(responseContentType, int64(msg.BodySize), bodyLogContentTypes, maxBodyLogBytes) {
+					msg.ResponseBody = rbw.body.String()
+					msg.ResponseBodyTruncated = rbw.body.Truncated()
+				}
+			}
+
+			// 命中 Sampler 采样规则时跳过此次访问日志，4xx/5xx 及带 c.Errors 的请求始终打印，不参与采样
+			if sampler != nil && len(c.Errors) == 0 && msg.StatusCode < http.StatusBadRequest {
+				if !sampler.allow(msg.Path + "|" + statusClass(msg.StatusCode)) {
+					return
+				}
 			}
 
 			// msg 设置完毕 创建 logger 进行打印
 			accessLogger := CtxLogger(c).Named("access_logger")
-			// handler 中使用 c.Error(err) 后，会打印到 context_errors 字段中
+			// trace_id/span_id 以及分类后的 c.Errors 都通过 fields 传给 Sink.WriteAccess，
+			// 而不是 With 到 logger 上——conf.Sink 非空时 logger 不会被用到，
+			// 烘焙在它身上的字段不会到达自定义 Sink（比如 rotating file / async sink）
+			var fields []zap.Field
+			fields = append(fields, zap.String("trace_id", traceID), zap.String("span_id", spanID))
+			// handler 中使用 c.Error(err) 后，按 gin.ErrorType 分类打印，并通过 ErrorHook 回调给上层
 			if len(c.Errors) > 0 {
-				accessLogger = accessLogger.With(zap.String("context_errors", c.Errors.String()))
+				bindErrors, publicErrors, privateErrors := classifyGinErrors(c.Errors)
+				if len(bindErrors) > 0 {
+					fields = append(fields, zap.Strings("bind_errors", bindErrors))
+				}
+				if len(publicErrors) > 0 {
+					fields = append(fields, zap.Strings("public_errors", publicErrors))
+				}
+				if len(privateErrors) > 0 {
+					fields = append(fields, zap.Strings("private_errors", privateErrors))
+				}
+				if conf.ErrorHook != nil {
+					conf.ErrorHook(c, c.Errors)
+				}
 			}
 
 			// details logger 打印 details msg 字段
@@ -228,27 +499,41 @@ func GinLoggerWithConfig(conf GinLoggerConfig) gin.HandlerFunc {
 				logger = accessLogger
 			}
 
-			// 打印访问日志，根据状态码确定日志打印级别
-			log := logger.Info
+			// 根据状态码确定日志打印级别
+			level := zapcore.InfoLevel
 			if msg.StatusCode >= http.StatusInternalServerError {
 				// 500+ 始终打印带 details 的 error 级别日志，并附带请求信息
 				requestDumps, _ := httputil.DumpRequest(c.Request, true)
-				log = detailsLogger.With(zap.String("request", string(requestDumps))).Error
+				logger = detailsLogger
+				fields = append(fields, zap.String("request", string(requestDumps)))
+				level = zapcore.ErrorLevel
 			} else if msg.StatusCode >= http.StatusBadRequest {
 				// 400+ 默认使用 warn 级别。如果有 errors 则使用 error 级别
-				log = logger.Warn
+				level = zapcore.WarnLevel
 				if len(c.Errors) > 0 {
-					log = logger.Error
+					level = zapcore.ErrorLevel
 				}
 			} else if len(c.Errors) > 0 {
-				log = logger.Error
+				level = zapcore.ErrorLevel
 			}
-			log(formatter(msg))
+
+			// 交由 Sink 完成最终写出，未配置时默认基于当前 logger 构造 NewZapSink，行为与之前版本一致
+			sink := conf.Sink
+			if sink == nil {
+				sink = NewZapSink(logger)
+			}
+			sink.WriteAccess(level, formatter(msg), msg, fields...)
 		}
 	}
 }
 
-// GetRequestBody 获取请求 body
+// GetRequestBody 获取请求 body，会把 body 整体读入内存后原样重置回 c.Request.Body
+//
+// Deprecated: 该函数没有大小限制，对大文件上传等场景存在 OOM/DoS 风险。
+// 如果只是为了在访问日志里记录 body，请使用 GinLoggerConfig.DetailsWithBody 搭配
+// MaxBodyLogBytes/BodyLogContentTypes（内部走 limitedBuffer + TeeReader 的流式截断路径）；
+// 业务 handler 确实需要完整 body 时，调用前请自行校验 Content-Length 或用
+// http.MaxBytesReader 等方式做好大小限制。
 func GetRequestBody(c *gin.Context) []byte {
 	// 获取请求 body
 	var requestBody []byte
@@ -268,7 +553,7 @@ func GetRequestBody(c *gin.Context) []byte {
 // 用于记录响应 body
 type responseBodyWriter struct {
 	gin.ResponseWriter
-	body *bytes.Buffer
+	body *limitedBuffer
 }
 
 // 覆盖 ResponseWriter 接口的 Write 方法，将 body 保存到 responseBodyWriter.body 中
@@ -277,6 +562,74 @@ func (w responseBodyWriter) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
+// limitedBuffer 是一个最多保留 max 字节的 buffer，超出部分不会被写入内存，只记录 Truncated 状态
+// 用于避免 DetailsWithBody 打开时，大文件上传/下载把整个 body 读进内存
+type limitedBuffer struct {
+	max       int
+	buf       bytes.Buffer
+	total     int
+	truncated bool
+}
+
+func newLimitedBuffer(max int) *limitedBuffer {
+	return &limitedBuffer{max: max}
+}
+
+// Write 永远返回 len(p), nil，即使发生截断，以保持标准 io.Writer 语义不给调用方返回读写错误
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.total += len(p)
+	if remain := b.max - b.buf.Len(); remain > 0 {
+		if remain > len(p) {
+			remain = len(p)
+		}
+		b.buf.Write(p[:remain])
+	}
+	if b.total > b.max {
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+// Truncated 返回写入内容是否超过了 max
+func (b *limitedBuffer) Truncated() bool {
+	return b.truncated
+}
+
+// String 返回保留下来的内容，截断时追加提示后缀
+func (b *limitedBuffer) String() string {
+	if !b.truncated {
+		return b.buf.String()
+	}
+	return fmt.Sprintf("%s...<%d bytes elided>", b.buf.String(), b.total-b.buf.Len())
+}
+
+// shouldCaptureBody 判断给定的 content type/content length 是否应该记录 body
+// 跳过 multipart、octet-stream 以及内容长度超过 maxBytes*4 的请求，避免读取大文件/二进制内容
+func shouldCaptureBody(contentType string, contentLength int64, allowedTypes []string, maxBytes int) bool {
+	if contentLength > int64(maxBytes)*4 {
+		return false
+	}
+
+	lower := strings.ToLower(contentType)
+	if strings.HasPrefix(lower, "multipart/") || strings.Contains(lower, "octet-stream") {
+		return false
+	}
+
+	for _, allowed := range allowedTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if strings.HasSuffix(allowed, "/*") {
+			if strings.HasPrefix(lower, strings.TrimSuffix(allowed, "*")) {
+				return true
+			}
+			continue
+		}
+		if strings.HasPrefix(lower, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
 // GinRecovery gin recovery 中间件
 // save err in context and abort 500 with do errhandler
 func GinRecovery(errHandler ...func(*gin.Context, ...interface{})) gin.HandlerFunc {
@@ -315,3 +668,131 @@ func GinRecovery(errHandler ...func(*gin.Context, ...interface{})) gin.HandlerFu
 		c.Next()
 	}
 }
+
+// CORSConfig GinCORS 支持的配置项字段定义
+type CORSConfig struct {
+	// AllowOrigins 允许的来源列表，支持精确匹配、"*" 通配以及形如 "https://*.example.com" 的前缀/后缀通配
+	// Optional. Default value is []string{"*"}
+	AllowOrigins []string
+	// AllowOriginFunc 自定义来源校验函数，优先级高于 AllowOrigins，可用于正则等复杂匹配场景
+	// Optional.
+	AllowOriginFunc func(origin string) bool
+	// AllowMethods 允许的请求方法，用于响应 preflight 请求
+	// Optional. Default value is []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+	AllowMethods []string
+	// AllowHeaders 允许的请求 header，用于响应 preflight 请求
+	// Optional.
+	AllowHeaders []string
+	// ExposeHeaders 允许浏览器端 JS 读取的响应 header，TraceIDKeyname 会被自动追加，无需重复配置
+	// Optional.
+	ExposeHeaders []string
+	// AllowCredentials 是否允许携带 cookie 等凭证信息
+	// Optional.
+	AllowCredentials bool
+	// MaxAge preflight 请求结果的缓存时间
+	// Optional. Default value is 12 * time.Hour
+	MaxAge time.Duration
+}
+
+// isOriginAllowed 判断 origin 是否命中 AllowOrigins 中的某一条规则
+// 支持精确匹配、"*" 全放行以及 "*" 前缀/后缀通配（如 "https://*.example.com"）
+func isOriginAllowed(origin string, allowed []string) bool {
+	for _, pattern := range allowed {
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		if !strings.Contains(pattern, "*") {
+			continue
+		}
+		regexPattern := "^" + regexp.QuoteMeta(pattern) + "$"
+		regexPattern = strings.ReplaceAll(regexPattern, regexp.QuoteMeta("*"), ".*")
+		if matched, err := regexp.MatchString(regexPattern, origin); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// GinCORS 以给定配置生成 gin 的 CORS 中间件
+// 处理 preflight OPTIONS 请求并设置 Access-Control-* 系列 header
+// TraceIDKeyname 会始终被追加到 Access-Control-Expose-Headers 中，
+// 以便与 GinLogger/GinLoggerWithConfig 链式使用时，前端 JS 也能读取到 trace id
+func GinCORS(conf CORSConfig) gin.HandlerFunc {
+	allowOrigins := conf.AllowOrigins
+	if len(allowOrigins) == 0 {
+		allowOrigins = []string{"*"}
+	}
+	allowMethods := conf.AllowMethods
+	if len(allowMethods) == 0 {
+		allowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+	}
+	maxAge := conf.MaxAge
+	if maxAge == 0 {
+		maxAge = 12 * time.Hour
+	}
+
+	exposeHeaders := make([]string, 0, len(conf.ExposeHeaders)+1)
+	exposeHeaders = append(exposeHeaders, conf.ExposeHeaders...)
+	traceHeader := string(TraceIDKeyname)
+	hasTraceHeader := false
+	for _, h := range exposeHeaders {
+		if strings.EqualFold(h, traceHeader) {
+			hasTraceHeader = true
+			break
+		}
+	}
+	if !hasTraceHeader {
+		exposeHeaders = append(exposeHeaders, traceHeader)
+	}
+	exposeHeadersValue := strings.Join(exposeHeaders, ", ")
+
+	return func(c *gin.Context) {
+		origin := c.Request.Header.Get("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		allowed := false
+		if conf.AllowOriginFunc != nil {
+			allowed = conf.AllowOriginFunc(origin)
+		} else {
+			allowed = isOriginAllowed(origin, allowOrigins)
+		}
+		if !allowed {
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		if conf.AllowCredentials {
+			header.Set("Access-Control-Allow-Origin", origin)
+			header.Set("Access-Control-Allow-Credentials", "true")
+			// 携带凭证时响应按 origin 回显，必须声明 Vary: Origin，否则共享缓存/CDN 可能把
+			// 这条针对某个 origin 的响应原样回放给另一个 origin 的请求
+			header.Add("Vary", "Origin")
+		} else if len(allowOrigins) == 1 && allowOrigins[0] == "*" && conf.AllowOriginFunc == nil {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+			header.Add("Vary", "Origin")
+		}
+		if exposeHeadersValue != "" {
+			header.Set("Access-Control-Expose-Headers", exposeHeadersValue)
+		}
+
+		if c.Request.Method == http.MethodOptions {
+			header.Set("Access-Control-Allow-Methods", strings.Join(allowMethods, ", "))
+			if len(conf.AllowHeaders) > 0 {
+				header.Set("Access-Control-Allow-Headers", strings.Join(conf.AllowHeaders, ", "))
+			} else if reqHeaders := c.Request.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", reqHeaders)
+			}
+			header.Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,180 @@
+package logging
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsOptions GinMetrics/GinMetricsHandler 支持的配置项字段定义
+type MetricsOptions struct {
+	// Registerer 自定义 prometheus 注册表
+	// Optional. Default value is prometheus.DefaultRegisterer
+	Registerer prometheus.Registerer
+	// Buckets http_request_duration_seconds histogram 的分桶边界
+	// Optional. Default value is prometheus.DefBuckets
+	Buckets []float64
+	// PathNormalizer 用于收敛路径基数，避免 `/users/:id` 这类路由或扫描器请求撑爆 label 基数
+	// Optional. Default 返回 c.FullPath()，未命中路由（如 404）时返回 "unknown"
+	PathNormalizer func(*gin.Context) string
+	// SkipPaths 与 GinLoggerConfig.SkipPaths 使用相同格式，配置一次即可同时应用到 GinLogger 与 GinMetrics
+	// Optional.
+	SkipPaths []string
+}
+
+// defaultMetricsPathNormalizer 默认的路径归一化函数
+func defaultMetricsPathNormalizer(c *gin.Context) string {
+	if p := c.FullPath(); p != "" {
+		return p
+	}
+	return "unknown"
+}
+
+// ginMetricsCollectors 持有 GinMetrics 用到的全部 prometheus collector
+type ginMetricsCollectors struct {
+	requestsTotal   *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	requestSize     *prometheus.HistogramVec
+	responseSize    *prometheus.HistogramVec
+	inFlight        prometheus.Gauge
+}
+
+// mustRegisterOrReuse 注册 collector，如果已经被注册过（如同一进程内多次调用 GinMetrics）则复用已存在的实例，
+// 避免因重复注册直接 panic
+func mustRegisterOrReuse(reg prometheus.Registerer, collector prometheus.Collector) prometheus.Collector {
+	if err := reg.Register(collector); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector
+		}
+		panic(err)
+	}
+	return collector
+}
+
+func newGinMetricsCollectors(reg prometheus.Registerer, buckets []float64) *ginMetricsCollectors {
+	labels := []string{"method", "path", "status", "handler"}
+
+	requestsTotal := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "gin 处理的 http 请求总数",
+	}, labels)
+	requestDuration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "gin 请求处理耗时分布",
+		Buckets: buckets,
+	}, labels)
+	requestSize := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_size_bytes",
+		Help:    "gin 请求 body 大小分布",
+		Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+	}, labels)
+	responseSize := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_response_size_bytes",
+		Help:    "gin 响应 body 大小分布",
+		Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+	}, labels)
+	inFlight := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "当前正在处理中的 http 请求数",
+	})
+
+	return &ginMetricsCollectors{
+		requestsTotal:   mustRegisterOrReuse(reg, requestsTotal).(*prometheus.CounterVec),
+		requestDuration: mustRegisterOrReuse(reg, requestDuration).(*prometheus.HistogramVec),
+		requestSize:     mustRegisterOrReuse(reg, requestSize).(*prometheus.HistogramVec),
+		responseSize:    mustRegisterOrReuse(reg, responseSize).(*prometheus.HistogramVec),
+		inFlight:        mustRegisterOrReuse(reg, inFlight).(prometheus.Gauge),
+	}
+}
+
+// GinMetrics 以给定配置生成 gin 的 Prometheus 指标采集中间件
+// 采集 http_requests_total / http_request_duration_seconds / http_request_size_bytes /
+// http_response_size_bytes / http_requests_in_flight，路径 label 经 PathNormalizer 收敛基数
+func GinMetrics(opts MetricsOptions) gin.HandlerFunc {
+	reg := opts.Registerer
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	buckets := opts.Buckets
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+	normalize := opts.PathNormalizer
+	if normalize == nil {
+		normalize = defaultMetricsPathNormalizer
+	}
+	collectors := newGinMetricsCollectors(reg, buckets)
+
+	var skip map[string]struct{}
+	if length := len(opts.SkipPaths); length > 0 {
+		skip = make(map[string]struct{}, length)
+		for _, p := range opts.SkipPaths {
+			skip[p] = struct{}{}
+		}
+	}
+
+	return func(c *gin.Context) {
+		if _, exists := skip[c.Request.URL.Path]; exists {
+			c.Next()
+			return
+		}
+
+		collectors.inFlight.Inc()
+		defer collectors.inFlight.Dec()
+
+		start := time.Now()
+		reqSize := approximateRequestSize(c.Request)
+
+		c.Next()
+
+		path := normalize(c)
+		status := strconv.Itoa(c.Writer.Status())
+		handler := c.HandlerName()
+
+		collectors.requestsTotal.WithLabelValues(c.Request.Method, path, status, handler).Inc()
+		collectors.requestDuration.WithLabelValues(c.Request.Method, path, status, handler).Observe(time.Since(start).Seconds())
+		collectors.requestSize.WithLabelValues(c.Request.Method, path, status, handler).Observe(float64(reqSize))
+		collectors.responseSize.WithLabelValues(c.Request.Method, path, status, handler).Observe(float64(c.Writer.Size()))
+	}
+}
+
+// approximateRequestSize 估算请求大小（请求行 + header + body），用于 http_request_size_bytes
+func approximateRequestSize(r *http.Request) int64 {
+	size := len(r.Method) + len(r.Proto) + len(r.Host)
+	if r.URL != nil {
+		size += len(r.URL.String())
+	}
+	for name, values := range r.Header {
+		size += len(name)
+		for _, v := range values {
+			size += len(v)
+		}
+	}
+	if r.ContentLength > 0 {
+		size += int(r.ContentLength)
+	}
+	return int64(size)
+}
+
+// GinMetricsHandler 返回暴露 /metrics 的 gin.HandlerFunc，opts.Registerer 同时实现了 prometheus.Gatherer
+// （如 *prometheus.Registry）时与传入 GinMetrics 的 opts.Registerer 共用同一份数据；
+// 为 nil 或未实现 prometheus.Gatherer 时读取的是 prometheus.DefaultGatherer
+func GinMetricsHandler(opts MetricsOptions) gin.HandlerFunc {
+	gatherer := gathererFromRegisterer(opts.Registerer)
+	h := promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+	return gin.WrapH(h)
+}
+
+func gathererFromRegisterer(reg prometheus.Registerer) prometheus.Gatherer {
+	if reg == nil {
+		return prometheus.DefaultGatherer
+	}
+	if gatherer, ok := reg.(prometheus.Gatherer); ok {
+		return gatherer
+	}
+	return prometheus.DefaultGatherer
+}
@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestGinMetrics(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	app := gin.New()
+	reg := prometheus.NewRegistry()
+	opts := MetricsOptions{Registerer: reg}
+	app.Use(GinMetrics(opts))
+	app.GET("/hello", hello)
+	app.GET("/metrics", GinMetricsHandler(opts))
+	go app.Run(":8895")
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := http.Get("http://localhost:8895/hello?k=v"); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get("http://localhost:8895/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected /metrics to return 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestGinMetricsPathNormalizerFallback(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	app := gin.New()
+	reg := prometheus.NewRegistry()
+	app.Use(GinMetrics(MetricsOptions{Registerer: reg}))
+	app.NoRoute(func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	go app.Run(":8896")
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := http.Get("http://localhost:8896/does-not-exist"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	metrics, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, mf := range metrics {
+		if mf.GetName() != "http_requests_total" {
+			continue
+		}
+		for _, m := range mf.Metric {
+			for _, l := range m.Label {
+				if l.GetName() == "path" && l.GetValue() == "unknown" {
+					found = true
+				}
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("expected unmatched route to be recorded under path=\"unknown\", got %+v", metrics)
+	}
+}
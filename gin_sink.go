@@ -0,0 +1,338 @@
+package logging
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Sink 定义 gin 访问日志的最终写出方式
+// GinLoggerWithConfig 默认基于当前请求的 logger 构造 NewZapSink，可通过 GinLoggerConfig.Sink
+// 替换为文件滚动、异步批量等实现
+type Sink interface {
+	// WriteAccess 写出一条访问日志，logLine 为 Formatter 生成的文本，
+	// fields 携带 trace_id/span_id、分类后的 c.Errors 以及 500 请求 dump 等附加信息——
+	// 这些字段不会烘焙在某个 logger 上，因此自定义 Sink（文件滚动、异步等）同样能拿到完整信息
+	WriteAccess(level zapcore.Level, logLine string, msg GinLogMsg, fields ...zap.Field)
+}
+
+// SinkCloser 可选接口，Sink 如果持有需要主动释放的资源（文件句柄、后台 goroutine 等）应实现该接口
+type SinkCloser interface {
+	Sink
+	// Close 等待已缓冲的记录写出完毕并释放资源，ctx 超时或取消时提前返回
+	Close(ctx context.Context) error
+}
+
+// CloseSink 在 conf.Sink 实现了 SinkCloser 时关闭它，用于服务优雅退出前 flush 掉尚未写出的访问日志
+func CloseSink(ctx context.Context, sink Sink) error {
+	closer, ok := sink.(SinkCloser)
+	if !ok {
+		return nil
+	}
+	return closer.Close(ctx)
+}
+
+// zapSink 是 Sink 的默认实现，直接写入调用方传入的 *zap.Logger
+type zapSink struct {
+	logger *zap.Logger
+}
+
+// NewZapSink 基于 *zap.Logger 构造 Sink，行为与引入 Sink 之前的默认实现一致
+func NewZapSink(logger *zap.Logger) Sink {
+	return &zapSink{logger: logger}
+}
+
+func (s *zapSink) WriteAccess(level zapcore.Level, logLine string, msg GinLogMsg, fields ...zap.Field) {
+	if ce := s.logger.Check(level, logLine); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// RotateOptions NewRotatingFileSink 的切割策略配置
+type RotateOptions struct {
+	// MaxSizeMB 单个日志文件达到该大小后触发切割
+	// Optional. Default 100
+	MaxSizeMB int
+	// MaxBackups 最多保留的历史文件数，超出的部分按修改时间从旧到新删除，0 表示不限制
+	// Optional.
+	MaxBackups int
+	// MaxAgeDays 历史文件最长保留天数，0 表示不限制
+	// Optional.
+	MaxAgeDays int
+}
+
+// rotatingFileSink 按大小切割文件，切割出的历史文件在后台异步 gzip 压缩
+type rotatingFileSink struct {
+	mu   sync.Mutex
+	path string
+	opts RotateOptions
+	file *os.File
+	size int64
+}
+
+// NewRotatingFileSink 构造一个按大小滚动、可自动清理历史文件的 Sink，不依赖 lumberjack 等第三方库
+func NewRotatingFileSink(path string, opts RotateOptions) (Sink, error) {
+	if opts.MaxSizeMB <= 0 {
+		opts.MaxSizeMB = 100
+	}
+	s := &rotatingFileSink{path: path, opts: opts}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *rotatingFileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
+
+func (s *rotatingFileSink) WriteAccess(level zapcore.Level, logLine string, msg GinLogMsg, fields ...zap.Field) {
+	line := logLine + "\n"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	maxSize := int64(s.opts.MaxSizeMB) * 1024 * 1024
+	if s.size+int64(len(line)) > maxSize {
+		if err := s.rotate(); err != nil {
+			// 切割失败时把错误打到 stderr，不能静默吞掉，否则 rotate 反复失败会造成访问日志无声丢失
+			fmt.Fprintf(os.Stderr, "rotate access log %s failed: %v\n", s.path, err)
+		}
+	}
+
+	if s.file == nil {
+		fmt.Fprintf(os.Stderr, "drop access log line, %s has no writable file: %s", s.path, line)
+		return
+	}
+	n, err := s.file.WriteString(line)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "write access log %s failed: %v\n", s.path, err)
+	}
+	s.size += int64(n)
+}
+
+// rotate 将当前文件改名为带时间戳的历史文件，异步 gzip 压缩，并按 MaxBackups/MaxAgeDays 清理旧文件
+// rename 失败时会以原路径重新打开文件继续写入；重新打开也失败时 s.file 为 nil，
+// 此时 WriteAccess 把该行打到 stderr 后丢弃，而不是写到已经 Close 掉的旧 fd 上静默丢失
+func (s *rotatingFileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+
+	rotated := s.rotatedName()
+	if err := os.Rename(s.path, rotated); err != nil {
+		if reopenErr := s.open(); reopenErr != nil {
+			return fmt.Errorf("rename %s to %s failed: %v (reopen also failed: %v)", s.path, rotated, err, reopenErr)
+		}
+		return fmt.Errorf("rename %s to %s failed: %v", s.path, rotated, err)
+	}
+	go compressAndRemove(rotated)
+
+	if err := s.open(); err != nil {
+		return fmt.Errorf("reopen %s after rotate failed: %v", s.path, err)
+	}
+	go s.prune()
+	return nil
+}
+
+// rotatedName 生成形如 path.YYYYMMDD-HHMMSS.N 的历史文件名，N 用于避免同一秒内多次切割时的命名冲突
+func (s *rotatingFileSink) rotatedName() string {
+	ts := time.Now().Format("20060102-150405")
+	for n := 0; ; n++ {
+		name := fmt.Sprintf("%s.%s.%d", s.path, ts, n)
+		// 压缩完成后原文件会被删除，只剩 name.gz，因此两者都不存在时才算可用
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			if _, err := os.Stat(name + ".gz"); os.IsNotExist(err) {
+				return name
+			}
+		}
+	}
+}
+
+func compressAndRemove(path string) {
+	src, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path + ".gz")
+	if err != nil {
+		return
+	}
+	gw := gzip.NewWriter(dst)
+	if _, err := io.Copy(gw, src); err == nil {
+		gw.Close()
+		dst.Close()
+		os.Remove(path)
+	} else {
+		gw.Close()
+		dst.Close()
+		os.Remove(path + ".gz")
+	}
+}
+
+func (s *rotatingFileSink) prune() {
+	matches, err := filepath.Glob(s.path + ".*")
+	if err != nil {
+		return
+	}
+
+	type backup struct {
+		path    string
+		modTime time.Time
+	}
+	backups := make([]backup, 0, len(matches))
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: m, modTime: info.ModTime()})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].modTime.After(backups[j].modTime) })
+
+	now := time.Now()
+	for i, b := range backups {
+		expired := s.opts.MaxAgeDays > 0 && now.Sub(b.modTime) > time.Duration(s.opts.MaxAgeDays)*24*time.Hour
+		overflow := s.opts.MaxBackups > 0 && i >= s.opts.MaxBackups
+		if expired || overflow {
+			os.Remove(b.path)
+		}
+	}
+}
+
+// Close 关闭当前写入的文件句柄
+func (s *rotatingFileSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+// DropPolicy 描述 NewAsyncSink 在缓冲区已满时如何处理新写入的记录
+type DropPolicy int
+
+const (
+	// DropPolicyBlock 阻塞等待缓冲区腾出空间，不丢日志但可能拖慢请求处理
+	DropPolicyBlock DropPolicy = iota
+	// DropPolicyDropNewest 直接丢弃当前这条新记录
+	DropPolicyDropNewest
+	// DropPolicyDropOldest 丢弃队列中最旧的一条记录，为新记录腾出空间
+	DropPolicyDropOldest
+)
+
+type asyncRecord struct {
+	level   zapcore.Level
+	logLine string
+	msg     GinLogMsg
+	fields  []zap.Field
+}
+
+// asyncSink 用 channel + 后台 goroutine 缓冲写入，避免访问日志量大时阻塞请求处理
+type asyncSink struct {
+	inner      Sink
+	dropPolicy DropPolicy
+	ch         chan asyncRecord
+	done       chan struct{}
+
+	// closeMu/closed 保护 ch 不会在还有 goroutine 正往里写的时候被关闭：
+	// WriteAccess 持有读锁发送，Close 需要拿到写锁（即等所有在途的 WriteAccess 都返回）才会关闭 ch，
+	// 避免并发场景下出现 "send on closed channel" panic
+	closeMu sync.RWMutex
+	closed  bool
+}
+
+// NewAsyncSink 包装 inner，将写入异步化，bufSize 为 channel 容量，dropPolicy 决定缓冲区满时的行为
+func NewAsyncSink(inner Sink, bufSize int, dropPolicy DropPolicy) Sink {
+	s := &asyncSink{
+		inner:      inner,
+		dropPolicy: dropPolicy,
+		ch:         make(chan asyncRecord, bufSize),
+		done:       make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+func (s *asyncSink) loop() {
+	defer close(s.done)
+	for rec := range s.ch {
+		s.inner.WriteAccess(rec.level, rec.logLine, rec.msg, rec.fields...)
+	}
+}
+
+func (s *asyncSink) WriteAccess(level zapcore.Level, logLine string, msg GinLogMsg, fields ...zap.Field) {
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+	if s.closed {
+		return
+	}
+
+	rec := asyncRecord{level: level, logLine: logLine, msg: msg, fields: fields}
+
+	switch s.dropPolicy {
+	case DropPolicyDropNewest:
+		select {
+		case s.ch <- rec:
+		default:
+		}
+	case DropPolicyDropOldest:
+		select {
+		case s.ch <- rec:
+		default:
+			select {
+			case <-s.ch:
+			default:
+			}
+			select {
+			case s.ch <- rec:
+			default:
+			}
+		}
+	default:
+		s.ch <- rec
+	}
+}
+
+// Close 停止接收新记录，等待缓冲区中的记录写完后关闭 inner（如果它也实现了 SinkCloser）
+func (s *asyncSink) Close(ctx context.Context) error {
+	s.closeMu.Lock()
+	if s.closed {
+		s.closeMu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.closeMu.Unlock()
+
+	close(s.ch)
+	select {
+	case <-s.done:
+		return CloseSink(ctx, s.inner)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
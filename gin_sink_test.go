@@ -0,0 +1,138 @@
+package logging
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRotatingFileSinkRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	sink, err := NewRotatingFileSink(path, RotateOptions{MaxSizeMB: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer CloseSink(context.Background(), sink)
+
+	rfs := sink.(*rotatingFileSink)
+	// 直接把内存中的累计大小顶到接近上限，避免测试真的写入 1MB 数据
+	rfs.size = int64(rfs.opts.MaxSizeMB)*1024*1024 - 10
+
+	sink.WriteAccess(zapcore.InfoLevel, "trigger rotate", GinLogMsg{})
+
+	// rotate 是异步压缩、同步 rename，等 gzip 后台 goroutine 跑完再检查
+	time.Sleep(200 * time.Millisecond)
+
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("expected exactly 1 rotated backup, got %v", matches)
+	}
+	if filepath.Ext(matches[0]) != ".gz" {
+		t.Fatalf("expected rotated backup to be gzip compressed, got %q", matches[0])
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected a fresh file to be reopened at %s: %v", path, err)
+	}
+}
+
+func TestRotatingFileSinkPrunesOldBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	sink, err := NewRotatingFileSink(path, RotateOptions{MaxSizeMB: 1, MaxBackups: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer CloseSink(context.Background(), sink)
+
+	rfs := sink.(*rotatingFileSink)
+	for i := 0; i < 3; i++ {
+		rfs.size = int64(rfs.opts.MaxSizeMB)*1024*1024 - 10
+		sink.WriteAccess(zapcore.InfoLevel, "trigger rotate", GinLogMsg{})
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("expected pruning to keep only MaxBackups=1 backup, got %v", matches)
+	}
+}
+
+func TestAsyncSinkDropPolicies(t *testing.T) {
+	block := make(chan struct{})
+	t.Cleanup(func() { close(block) })
+	inner := &blockingSink{block: block}
+
+	t.Run("DropNewest", func(t *testing.T) {
+		sink := NewAsyncSink(inner, 1, DropPolicyDropNewest).(*asyncSink)
+		// 第一条会被后台 loop 立刻取走并卡在 inner.WriteAccess 里，第二条才真正占满容量为 1 的 buffer
+		sink.ch <- asyncRecord{logLine: "processing"}
+		time.Sleep(20 * time.Millisecond)
+		sink.ch <- asyncRecord{logLine: "occupying"}
+
+		sink.WriteAccess(zapcore.InfoLevel, "dropped", GinLogMsg{})
+		if len(sink.ch) != 1 {
+			t.Fatalf("expected buffer to stay full at 1, got %d", len(sink.ch))
+		}
+		if rec := <-sink.ch; rec.logLine != "occupying" {
+			t.Fatalf("expected the new record to be dropped, buffer to still hold %q, got %q", "occupying", rec.logLine)
+		}
+	})
+
+	t.Run("DropOldest", func(t *testing.T) {
+		sink := NewAsyncSink(inner, 1, DropPolicyDropOldest).(*asyncSink)
+		sink.ch <- asyncRecord{logLine: "processing"}
+		time.Sleep(20 * time.Millisecond)
+		sink.ch <- asyncRecord{logLine: "oldest"}
+
+		sink.WriteAccess(zapcore.InfoLevel, "newest", GinLogMsg{})
+		rec := <-sink.ch
+		if rec.logLine != "newest" {
+			t.Fatalf("expected oldest record to be evicted in favor of newest, got %q", rec.logLine)
+		}
+	})
+}
+
+func TestAsyncSinkCloseDoesNotPanicOnConcurrentWrite(t *testing.T) {
+	sink := NewAsyncSink(&recordingSink{}, 16, DropPolicyBlock)
+
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				sink.WriteAccess(zapcore.InfoLevel, "line", GinLogMsg{})
+			}
+		}
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := CloseSink(ctx, sink); err != nil {
+		t.Fatal(err)
+	}
+	close(stop)
+}
+
+// blockingSink 用于在测试中把 asyncSink 的消费 goroutine 卡住，使其内部 channel 保持"满"的状态
+type blockingSink struct {
+	block chan struct{}
+}
+
+func (s *blockingSink) WriteAccess(level zapcore.Level, logLine string, msg GinLogMsg, fields ...zap.Field) {
+	<-s.block
+}
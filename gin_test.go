@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func hello(c *gin.Context) {
@@ -50,3 +54,237 @@ func TestGinLoggerWithConfig(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestGinCORS(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	app := gin.New()
+	app.Use(GinLogger())
+	app.Use(GinCORS(CORSConfig{
+		AllowOrigins:     []string{"https://*.example.com"},
+		AllowCredentials: true,
+	}))
+	app.GET("/hello", hello)
+	go app.Run(":8889")
+	time.Sleep(100 * time.Millisecond)
+
+	req, err := http.NewRequest(http.MethodGet, "http://localhost:8889/hello", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Origin", "https://app.example.com")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expose := resp.Header.Get("Access-Control-Expose-Headers"); !strings.Contains(expose, string(TraceIDKeyname)) {
+		t.Fatalf("expected Access-Control-Expose-Headers to contain trace id header, got %q", expose)
+	}
+}
+
+// recordingSink 记录 WriteAccess 被调用的次数、最近一次的 msg 以及 fields，
+// 用于验证 GinLoggerConfig.Sink 被正确接管，以及 trace_id/span_id、分类后的 c.Errors
+// 是否真的通过 fields 传到了 Sink，而不是只烘焙在被丢弃的 logger 上
+type recordingSink struct {
+	count      int
+	lastMsg    GinLogMsg
+	lastFields []zap.Field
+}
+
+func (s *recordingSink) WriteAccess(level zapcore.Level, logLine string, msg GinLogMsg, fields ...zap.Field) {
+	s.count++
+	s.lastMsg = msg
+	s.lastFields = fields
+}
+
+// fieldsToMap 把 []zap.Field 解码成 key -> value 的 map，用于在测试中断言具体字段的值，
+// 避免依赖 zap.Field 未导出的内部布局
+func fieldsToMap(fields []zap.Field) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return enc.Fields
+}
+
+func TestGinLoggerWithConfigSink(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	app := gin.New()
+	sink := &recordingSink{}
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{Sink: sink}))
+	app.GET("/hello", hello)
+	go app.Run(":8890")
+	time.Sleep(100 * time.Millisecond)
+
+	_, err := http.Get("http://localhost:8890/hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if sink.count != 1 {
+		t.Fatalf("expected sink to receive 1 access record, got %d", sink.count)
+	}
+
+	// trace_id/span_id 必须通过 fields 传给 Sink，而不是只烘焙在 GinLoggerWithConfig 内部、
+	// conf.Sink 非空时即被丢弃的 logger 上
+	got := fieldsToMap(sink.lastFields)
+	if traceID, _ := got["trace_id"].(string); traceID == "" {
+		t.Fatalf("expected trace_id field to reach Sink.WriteAccess, got fields %+v", got)
+	}
+	if spanID, _ := got["span_id"].(string); spanID == "" {
+		t.Fatalf("expected span_id field to reach Sink.WriteAccess, got fields %+v", got)
+	}
+}
+
+// bindErrorHandler 产生一个 ErrorTypeBind 的 c.Errors，用于验证 classifyGinErrors 按类型分类后
+// 仍然能通过 fields 到达 Sink
+func bindErrorHandler(c *gin.Context) {
+	c.Error(errors.New("bad request")).SetType(gin.ErrorTypeBind)
+	c.JSON(http.StatusOK, "world")
+}
+
+func TestGinLoggerWithConfigSinkClassifiedErrorFields(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	app := gin.New()
+	sink := &recordingSink{}
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{Sink: sink}))
+	app.GET("/hello", hello)
+	app.GET("/bind-error", bindErrorHandler)
+	go app.Run(":8897")
+	time.Sleep(100 * time.Millisecond)
+
+	// hello 里的 c.Error 没有指定 Type，按 classifyGinErrors 归为 private_errors
+	if _, err := http.Get("http://localhost:8897/hello"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	got := fieldsToMap(sink.lastFields)
+	privateErrors, _ := got["private_errors"].([]interface{})
+	if len(privateErrors) != 2 || privateErrors[0] != "test1" || privateErrors[1] != "test2" {
+		t.Fatalf("expected private_errors field to carry classified c.Errors, got fields %+v", got)
+	}
+
+	if _, err := http.Get("http://localhost:8897/bind-error"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	got = fieldsToMap(sink.lastFields)
+	bindErrors, _ := got["bind_errors"].([]interface{})
+	if len(bindErrors) != 1 || bindErrors[0] != "bad request" {
+		t.Fatalf("expected bind_errors field to carry the ErrorTypeBind error, got fields %+v", got)
+	}
+}
+
+func echoBody(c *gin.Context) {
+	body, _ := ioutil.ReadAll(c.Request.Body)
+	c.String(http.StatusOK, "%d", len(body))
+}
+
+func TestGinLoggerWithConfigBodyTruncation(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	app := gin.New()
+	sink := &recordingSink{}
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		DetailsWithBody: true,
+		MaxBodyLogBytes: 8,
+		Sink:            sink,
+	}))
+	app.POST("/echo", echoBody)
+	go app.Run(":8891")
+	time.Sleep(100 * time.Millisecond)
+
+	payload := `{"data": "12345678901"}`
+	resp, err := http.Post("http://localhost:8891/echo", "application/json", bytes.NewReader([]byte(payload)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	got, _ := ioutil.ReadAll(resp.Body)
+	if string(got) != fmt.Sprint(len(payload)) {
+		t.Fatalf("expected handler to see the full body of length %d, got %q", len(payload), got)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if !sink.lastMsg.RequestBodyTruncated {
+		t.Fatalf("expected RequestBodyTruncated to be true, got msg %+v", sink.lastMsg)
+	}
+	if !strings.Contains(sink.lastMsg.RequestBody, "bytes elided") {
+		t.Fatalf("expected RequestBody to contain elided suffix, got %q", sink.lastMsg.RequestBody)
+	}
+}
+
+func TestGinLoggerWithConfigTraceparent(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	app := gin.New()
+	app.Use(GinLogger())
+	app.GET("/hello", hello)
+	go app.Run(":8892")
+	time.Sleep(100 * time.Millisecond)
+
+	// 未携带 traceparent 时，中间件应生成一个合法的 traceparent 并写回 response header
+	resp, err := http.Get("http://localhost:8892/hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp := resp.Header.Get("traceparent")
+	if _, _, ok := parseTraceparent(tp); !ok {
+		t.Fatalf("expected a valid generated traceparent, got %q", tp)
+	}
+
+	// 携带合法 traceparent 时，trace-id 应作为 canonical trace id 被透传
+	req, err := http.NewRequest(http.MethodGet, "http://localhost:8892/hello", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const incoming = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req.Header.Set("traceparent", incoming)
+	resp2, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp2.Header.Get(string(TraceIDKeyname)); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected trace id propagated from traceparent, got %q", got)
+	}
+}
+
+func TestGinLoggerWithConfigSampler(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	app := gin.New()
+	sink := &recordingSink{}
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		Sink:    sink,
+		Sampler: &Sampler{Initial: 1, Thereafter: 100, Tick: time.Minute},
+	}))
+	app.GET("/ok", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	go app.Run(":8893")
+	time.Sleep(100 * time.Millisecond)
+
+	for i := 0; i < 5; i++ {
+		if _, err := http.Get("http://localhost:8893/ok"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	if sink.count != 1 {
+		t.Fatalf("expected sampler to drop repeat 2xx requests, got %d access records", sink.count)
+	}
+}
+
+func TestGinLoggerWithConfigErrorHook(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	app := gin.New()
+	var hooked []*gin.Error
+	app.Use(GinLoggerWithConfig(GinLoggerConfig{
+		ErrorHook: func(c *gin.Context, errs []*gin.Error) { hooked = errs },
+	}))
+	app.GET("/hello", hello)
+	go app.Run(":8894")
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := http.Get("http://localhost:8894/hello"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(hooked) != 2 {
+		t.Fatalf("expected ErrorHook to receive 2 errors, got %d", len(hooked))
+	}
+}